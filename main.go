@@ -1,36 +1,217 @@
 package main
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"flag"
 	"fmt"
 	"go/ast"
 	"go/build"
 	"go/parser"
 	"go/token"
+	"io"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 )
 
+func init() {
+	// checkTransitiveImports resolves each import via build.Import, which for
+	// a non-relative path shells out to "go list" whenever GO111MODULE isn't
+	// explicitly "off" (the default since Go 1.16) -- and runs that
+	// subprocess against the process's real working directory, not the
+	// srcDir bandep passes in. If that "go list" call fails for any reason
+	// other than "not using modules", build.Import returns the error
+	// immediately and never falls back to the GOPATH-style walk that
+	// build.Default.GOPATH/SrcDirs (also relied on by MatchPackages) assume.
+	// bandep's whole resolution model predates modules, so pin that mode
+	// explicitly instead of depending on GO111MODULE happening to be unset
+	// in whatever environment bandep runs in.
+	os.Setenv("GO111MODULE", "off")
+}
+
 // nolint: gochecknoglobals
 var (
-	version = "dev"
-	pkg     = flag.String("pkg", "./...", "package to check")
-	bansStr = flag.String("ban", "", "import paths to ban (comma separated list)")
-	help    = flag.Bool("help", false, "show context-sensitive help.")
-	vers    = flag.Bool("version", false, "show application version.")
+	version    = "dev"
+	pkg        = flag.String("pkg", "./...", "package to check")
+	skipStr    = flag.String("skip", "", "package patterns to skip (comma separated list)")
+	bansStr    = flag.String("ban", "", "import paths to ban (comma separated list)")
+	formatStr  = flag.String("format", "text", "output format: text, json or checkstyle")
+	transitive = flag.Bool("transitive", false, "also check imports of imports, recursively")
+	maxDepth   = flag.Int("max-depth", 5, "how deep to follow transitive imports")
+	includeStd = flag.Bool("include-std", false, "don't skip standard library packages when checking transitively")
+	help       = flag.Bool("help", false, "show context-sensitive help.")
+	vers       = flag.Bool("version", false, "show application version.")
 )
 
-type bannedError struct {
-	Package string
-	Imports []string
+// checkOptions controls the optional transitive-import gate.
+type checkOptions struct {
+	transitive bool
+	maxDepth   int
+	includeStd bool
+}
+
+// pkgRef is a package resolved from a -pkg pattern: Name is the import
+// path (or ./-relative path, in filesystem mode) that -skip patterns and
+// violation.Package are matched and reported against, while Dir is the
+// filesystem directory parser.ParseDir needs to actually read it. In
+// filesystem mode the two are the same string.
+type pkgRef struct {
+	Name string
+	Dir  string
+}
+
+// violation is a single banned import found in a single file, ready to
+// be rendered by any of the supported -format outputs.
+type violation struct {
+	Package string `json:"package"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Import  string `json:"import"`
+	Ban     string `json:"ban"`
+}
+
+// importSite is the file and line a direct import was found at, used to
+// attribute transitive violations back to the source line that pulled
+// in the chain that led to them.
+type importSite struct {
+	File string
+	Line int
+}
+
+// before reports whether a is an earlier occurrence than b, giving a
+// deterministic "first seen" import site regardless of the order
+// go/parser's per-file map happens to be iterated in.
+func (a importSite) before(b importSite) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	return a.Line < b.Line
+}
+
+// bannedImport is an import that matched one of the -ban patterns,
+// together with the human-readable pattern that matched it and the
+// line it was found on.
+type bannedImport struct {
+	Path    string
+	Pattern string
+	Line    int
+}
+
+// banRule is a compiled -ban entry. A rule whose pattern is prefixed
+// with "!" is an allow rule: it carves an exception out of a broader
+// ban rather than banning anything itself.
+type banRule struct {
+	pattern string
+	allow   bool
+	match   func(string) bool
+}
+
+// report renders violations to stdout in the requested format.
+func report(format string, violations []violation) error {
+	switch format {
+	case "", "text":
+		return reportText(os.Stdout, violations)
+	case "json":
+		return reportJSON(os.Stdout, violations)
+	case "checkstyle":
+		return reportCheckstyle(os.Stdout, violations)
+	default:
+		return fmt.Errorf("unknown -format: %q", format)
+	}
+}
+
+func reportText(w io.Writer, violations []violation) error {
+	for _, v := range violations {
+		if _, err := fmt.Fprintf(w, "%s:%d: %s imports banned dependency %q (matches %q)\n",
+			v.File, v.Line, v.Package, v.Import, v.Ban); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reportJSON(w io.Writer, violations []violation) error {
+	if violations == nil {
+		violations = []violation{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(violations)
+}
+
+// checkstyleReport is the subset of the checkstyle XML schema understood
+// by reviewdog, Jenkins and GitLab's code-quality report.
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
 }
 
-func (e bannedError) Error() string {
-	return fmt.Sprintf("%s is using banned dependencies %s", e.Package, strings.Join(e.Imports, ", "))
+func reportCheckstyle(w io.Writer, violations []violation) error {
+	var order []string
+	files := map[string]*checkstyleFile{}
+	for _, v := range violations {
+		f, ok := files[v.File]
+		if !ok {
+			f = &checkstyleFile{Name: v.File}
+			files[v.File] = f
+			order = append(order, v.File)
+		}
+		f.Errors = append(f.Errors, checkstyleError{
+			Line:     v.Line,
+			Severity: "error",
+			Message:  fmt.Sprintf("%s imports banned dependency %s (matches %s)", v.Package, v.Import, v.Ban),
+			Source:   "bandep",
+		})
+	}
+
+	out := checkstyleReport{Version: "4.3"}
+	for _, name := range order {
+		out.Files = append(out.Files, *files[name])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// compileBans turns the raw -ban entries into banRules, compiling each
+// pattern's matcher once so it isn't rebuilt for every file checked.
+func compileBans(bans []string) []banRule {
+	var rules []banRule
+	for _, ban := range bans {
+		if ban == "" {
+			continue
+		}
+		allow := strings.HasPrefix(ban, "!")
+		pattern := strings.TrimPrefix(ban, "!")
+		rules = append(rules, banRule{pattern: pattern, allow: allow, match: matchPattern(pattern)})
+	}
+	return rules
 }
 
 func main() {
@@ -42,10 +223,15 @@ func main() {
 enforce banned dependency imports
 
 Flags:
-  -h, --help              Show context-sensitive help.
-      --pkg="./..."       Package to check.
-      --ban=BAN1,BAN2,... Import paths to ban (comma separated list).
-  -v, --version           Show application version.`)
+  -h, --help                Show context-sensitive help.
+      --pkg="./..."         Package to check.
+      --skip=SKIP1,SKIP2,.. Package patterns to skip (comma separated list).
+      --ban=BAN1,BAN2,...   Import paths to ban (comma separated list).
+      --format="text"       Output format: text, json or checkstyle.
+      --transitive          Also check imports of imports, recursively.
+      --max-depth=5         How deep to follow transitive imports.
+      --include-std         Don't skip standard library packages when checking transitively.
+  -v, --version             Show application version.`)
 	}
 	flag.Parse()
 
@@ -64,61 +250,270 @@ Flags:
 		bans[i] = strings.TrimSpace(ban)
 	}
 
-	if err := check(*pkg, bans); err != nil {
-		fmt.Fprintf(os.Stderr, "%v", err)
+	var skips []string
+	if *skipStr != "" {
+		skips = strings.Split(*skipStr, ",")
+		for i, skip := range skips {
+			skips[i] = strings.TrimSpace(skip)
+		}
+	}
+
+	opts := checkOptions{
+		transitive: *transitive,
+		maxDepth:   *maxDepth,
+		includeStd: *includeStd,
+	}
+
+	violations, errCount := check(*pkg, skips, bans, opts)
+
+	if err := report(*formatStr, violations); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if len(violations) > 0 || errCount > 0 {
 		os.Exit(1)
 	}
 }
 
-func check(path string, bans []string) error {
-	if !strings.HasSuffix(path, "/...") {
-		return checkPkg(path, bans)
+// check resolves pattern into packages, checks every one of them against
+// bans, and returns every violation found across the whole tree instead
+// of stopping at the first offending package. errCount is the number of
+// packages that could not be checked at all (e.g. a parser.ParseDir
+// failure); a CI gate needs that distinguished from "checked clean", since
+// a package bandep never managed to read isn't a package it cleared.
+func check(pattern string, skips, bans []string, opts checkOptions) (violations []violation, errCount int) {
+	for _, pkg := range filterSkipped(resolvePackages(pattern), skips) {
+		pkgViolations, err := checkPkg(pkg, bans, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			errCount++
+			continue
+		}
+		violations = append(violations, pkgViolations...)
 	}
-	for _, pkg := range allPackagesInFS(path) {
-		if err := checkPkg(pkg, bans); err != nil {
-			return err
+	return violations, errCount
+}
+
+// resolvePackages expands pattern into the concrete set of packages it
+// refers to: a filesystem tree rooted at ./ or ../, an import-path
+// pattern (possibly using the all/std/cmd meta-names), a single relative
+// directory given as-is, or a single absolute import path (e.g. a single
+// installed dependency), resolved to its directory via build.Import the
+// same way MatchPackages and checkTransitiveImports already do.
+func resolvePackages(pattern string) []pkgRef {
+	switch {
+	case (strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "../")) && strings.Contains(pattern, "..."):
+		return allPackagesInFS(pattern)
+	case pattern == "all" || pattern == "std" || pattern == "cmd" || strings.Contains(pattern, "..."):
+		return MatchPackages(pattern)
+	case strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "../"):
+		return []pkgRef{{Name: pattern, Dir: pattern}}
+	default:
+		bp, err := build.Import(pattern, ".", 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %q: %v\n", pattern, err)
+			return nil
 		}
+		return []pkgRef{{Name: pattern, Dir: bp.Dir}}
 	}
-	return nil
 }
 
-func checkPkg(pkg string, bans []string) error {
-	packs, err := parser.ParseDir(token.NewFileSet(), pkg, nil, 0)
+// filterSkipped drops from pkgs any package whose Name matches one of the
+// skip patterns, which use the same ... wildcard grammar as -pkg. Name,
+// not Dir, is what users write -skip patterns against.
+func filterSkipped(pkgs []pkgRef, skips []string) []pkgRef {
+	if len(skips) == 0 {
+		return pkgs
+	}
+
+	var matchers []func(string) bool
+	for _, skip := range skips {
+		if skip == "" {
+			continue
+		}
+		matchers = append(matchers, matchPattern(skip))
+	}
+
+	var result []pkgRef
+pkgLoop:
+	for _, pkg := range pkgs {
+		for _, match := range matchers {
+			if match(pkg.Name) {
+				continue pkgLoop
+			}
+		}
+		result = append(result, pkg)
+	}
+	return result
+}
+
+func checkPkg(pkg pkgRef, bans []string, opts checkOptions) ([]violation, error) {
+	rules := compileBans(bans)
+
+	fset := token.NewFileSet()
+	packs, err := parser.ParseDir(fset, pkg.Dir, nil, 0)
 	if err != nil {
-		return fmt.Errorf("failed to parse pkg: %s: %s", pkg, err.Error())
+		return nil, fmt.Errorf("failed to parse pkg: %s: %s", pkg.Name, err.Error())
 	}
+
+	var violations []violation
+	origins := map[string]importSite{}
 	for _, pack := range packs {
-		for _, file := range pack.Files {
-			imports := checkBannedImports(file, bans)
-			if len(imports) > 0 {
-				return bannedError{
-					Package: pkg,
-					Imports: imports,
+		for filename, file := range pack.Files {
+			for _, imp := range checkBannedImports(fset, file, rules) {
+				violations = append(violations, violation{
+					Package: pkg.Name,
+					File:    filename,
+					Line:    imp.Line,
+					Import:  imp.Path,
+					Ban:     imp.Pattern,
+				})
+			}
+			if opts.transitive {
+				for _, imp := range file.Imports {
+					path := strings.Replace(imp.Path.Value, `"`, "", -1)
+					site := importSite{File: filename, Line: fset.Position(imp.Pos()).Line}
+					if existing, ok := origins[path]; !ok || site.before(existing) {
+						origins[path] = site
+					}
 				}
 			}
 		}
 	}
-	return nil
+
+	if opts.transitive {
+		imports := make([]string, 0, len(origins))
+		for imp := range origins {
+			imports = append(imports, imp)
+		}
+		sort.Strings(imports)
+		violations = append(violations, checkTransitiveImports(pkg.Name, pkg.Dir, imports, origins, rules, opts)...)
+	}
+
+	return violations, nil
 }
 
-func checkBannedImports(file *ast.File, bans []string) []string {
-	var result []string
+func checkBannedImports(fset *token.FileSet, file *ast.File, rules []banRule) []bannedImport {
+	var result []bannedImport
 	for _, imp := range file.Imports {
-		var path = imp.Path.Value
-		path = strings.Replace(path, `"`, "", -1)
-		for _, ban := range bans {
-			if ban == path {
-				result = append(result, path)
-			}
+		path := strings.Replace(imp.Path.Value, `"`, "", -1)
+
+		pattern, banned := matchBanned(rules, path)
+		if !banned {
+			continue
 		}
+
+		result = append(result, bannedImport{
+			Path:    path,
+			Pattern: pattern,
+			Line:    fset.Position(imp.Pos()).Line,
+		})
 	}
 	return result
 }
 
+// matchBanned reports whether path matches a banning rule and no
+// exception ("!"-prefixed) rule overrides it, returning the banning
+// pattern for use in error messages.
+func matchBanned(rules []banRule, path string) (pattern string, banned bool) {
+	for _, rule := range rules {
+		if !rule.allow && rule.match(path) {
+			pattern = rule.pattern
+			banned = true
+			break
+		}
+	}
+	if !banned {
+		return "", false
+	}
+	for _, rule := range rules {
+		if rule.allow && rule.match(path) {
+			return "", false
+		}
+	}
+	return pattern, true
+}
+
+// checkTransitiveImports follows each of a package's direct imports down
+// through build.Import, up to opts.maxDepth levels, looking for banned
+// packages that aren't visible from a direct scan of the source files.
+// Direct imports themselves are only descended into, not re-reported:
+// checkBannedImports already caught those, with a file and line number.
+// Each match's violation.Import is the dependency chain that pulled the
+// banned package in, e.g. "mypkg -> foo -> bar -> banned/pkg", and its
+// File/Line are where the chain's direct import was found, since that's
+// the line a maintainer needs to change to drop the dependency. pkgDir is
+// the filesystem directory build.Import resolves each import relative to;
+// pkgName is the import path used in the reported chain and Package field.
+//
+// visited tracks, per import path, the shallowest depth it has been
+// reached at rather than a plain seen/unseen bool, because a single
+// direct import's subtree can reach the same package at different
+// depths (a diamond in that import's own dependency graph). If the
+// first route to reach it happened to be the deep one, its remaining
+// budget may run out before reaching that package's own deeper
+// descendants; a later, more shallow route to the same package needs to
+// re-walk it so those descendants still get explored. Depths only
+// increase around any given chain, so revisiting solely on improvement
+// still terminates on cycles. visited is reset for every top-level
+// direct import rather than shared across all of them: two distinct
+// direct imports converging on the same package are two distinct import
+// sites a maintainer needs to see reported, not one that should
+// suppress the other just because it was walked first.
+func checkTransitiveImports(pkgName, pkgDir string, imports []string, origins map[string]importSite, rules []banRule, opts checkOptions) []violation {
+	var violations []violation
+
+	for _, imp := range imports {
+		visited := map[string]int{}
+
+		var walk func(importPath, srcDir string, chain []string, depth int, checkSelf bool, origin importSite)
+		walk = func(importPath, srcDir string, chain []string, depth int, checkSelf bool, origin importSite) {
+			if seen, ok := visited[importPath]; ok && seen <= depth {
+				return
+			}
+			visited[importPath] = depth
+
+			bp, err := build.Import(importPath, srcDir, 0)
+			if err != nil {
+				return
+			}
+			if bp.Goroot && !opts.includeStd {
+				return
+			}
+
+			chain = append(append([]string{}, chain...), importPath)
+
+			if checkSelf {
+				if pattern, banned := matchBanned(rules, importPath); banned {
+					violations = append(violations, violation{
+						Package: pkgName,
+						File:    origin.File,
+						Line:    origin.Line,
+						Import:  strings.Join(chain, " -> "),
+						Ban:     pattern,
+					})
+				}
+			}
+
+			if depth > opts.maxDepth {
+				return
+			}
+			for _, imp := range bp.Imports {
+				walk(imp, bp.Dir, chain, depth+1, true, origin)
+			}
+		}
+
+		walk(imp, pkgDir, []string{pkgName}, 1, false, origins[imp])
+	}
+
+	return violations
+}
+
 // allPackagesInFS is like allPackages but is passed a pattern
 // beginning ./ or ../, meaning it should scan the tree rooted
 // at the given directory.  There are ... in the pattern too.
-func allPackagesInFS(pattern string) []string {
+func allPackagesInFS(pattern string) []pkgRef {
 	pkgs, err := matchPackagesInFS(pattern)
 	if len(pkgs) == 0 {
 		fmt.Fprintf(os.Stderr, "warning: %q matched no packages\n", pattern)
@@ -129,7 +524,7 @@ func allPackagesInFS(pattern string) []string {
 	return pkgs
 }
 
-func matchPackagesInFS(pattern string) ([]string, error) {
+func matchPackagesInFS(pattern string) ([]pkgRef, error) {
 	// Find directory to begin the scan.
 	// Could be smarter but this one optimization
 	// is enough for now, since ... is usually at the
@@ -147,7 +542,7 @@ func matchPackagesInFS(pattern string) ([]string, error) {
 	}
 	match := matchPattern(pattern)
 
-	var pkgs []string
+	var pkgs []pkgRef
 	var err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
 		if err != nil || !fi.IsDir() {
 			return nil
@@ -181,12 +576,89 @@ func matchPackagesInFS(pattern string) ([]string, error) {
 			}
 			return nil
 		}
-		pkgs = append(pkgs, name)
+		// In filesystem mode the import path and the directory parser.ParseDir
+		// needs are the same string.
+		pkgs = append(pkgs, pkgRef{Name: name, Dir: name})
 		return nil
 	})
 	return pkgs, err
 }
 
+// MatchPackages is like matchPackagesInFS but is passed a pattern
+// that does not begin with ./ or ../, meaning it should be interpreted
+// as an import path (possibly with a trailing ... wildcard) or one of
+// the meta-names "all", "std" or "cmd", the same as the go tool accepts.
+// It walks every root in build.Default.SrcDirs(), skipping GOROOT/src
+// unless the pattern is "std" or rooted at "cmd" (e.g. "cmd" itself or
+// "cmd/compile/..."), and returns both the import path of each matched
+// package, for matching and reporting, and its filesystem directory, for
+// parser.ParseDir.
+func MatchPackages(pattern string) []pkgRef {
+	isCmd := pattern == "cmd" || strings.HasPrefix(pattern, "cmd/")
+
+	match := func(string) bool { return true }
+	if pattern != "all" && pattern != "std" && pattern != "cmd" {
+		match = matchPattern(pattern)
+	}
+
+	var pkgs []pkgRef
+	for _, src := range build.Default.SrcDirs() {
+		isGoroot := src == filepath.Join(build.Default.GOROOT, "src")
+		if pattern == "std" || isCmd {
+			if !isGoroot {
+				continue
+			}
+		} else if isGoroot {
+			continue
+		}
+
+		root := filepath.Clean(src) + string(filepath.Separator)
+		err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil || !fi.IsDir() || path == root {
+				return nil
+			}
+
+			// Avoid .foo, _foo, and testdata directory trees, but do not avoid "." or "..".
+			_, elem := filepath.Split(path)
+			dot := strings.HasPrefix(elem, ".") && elem != "." && elem != ".."
+			if dot || strings.HasPrefix(elem, "_") || elem == "testdata" || elem == "vendor" {
+				return filepath.SkipDir
+			}
+
+			name := filepath.ToSlash(path[len(root):])
+			if pattern == "std" && strings.Contains(name, ".") {
+				// Internal tool packages live under import paths with dots, e.g. golang.org/x.
+				return filepath.SkipDir
+			}
+			if pattern == "std" && (name == "cmd" || strings.HasPrefix(name, "cmd/")) {
+				// cmd is its own meta-name, disjoint from std, same as "go list std".
+				return filepath.SkipDir
+			}
+			if isCmd && name != "cmd" && !strings.HasPrefix(name, "cmd/") {
+				return filepath.SkipDir
+			}
+			if !match(name) {
+				return nil
+			}
+			if _, err := build.ImportDir(path, 0); err != nil {
+				if _, noGo := err.(*build.NoGoError); !noGo {
+					log.Print(err)
+				}
+				return nil
+			}
+			pkgs = append(pkgs, pkgRef{Name: name, Dir: path})
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %q: %v\n", pattern, err)
+		}
+	}
+	if len(pkgs) == 0 {
+		fmt.Fprintf(os.Stderr, "warning: %q matched no packages\n", pattern)
+	}
+	return pkgs
+}
+
 // matchPattern(pattern)(name) reports whether
 // name matches pattern.  Pattern is a limited glob
 // pattern in which '...' means 'any string' and there
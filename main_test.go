@@ -0,0 +1,593 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"go/build"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchBanned(t *testing.T) {
+	tests := []struct {
+		name        string
+		bans        []string
+		path        string
+		wantBanned  bool
+		wantPattern string
+	}{
+		{
+			name:       "no bans",
+			bans:       nil,
+			path:       "github.com/sirupsen/logrus",
+			wantBanned: false,
+		},
+		{
+			name:        "exact match",
+			bans:        []string{"github.com/sirupsen/logrus"},
+			path:        "github.com/sirupsen/logrus",
+			wantBanned:  true,
+			wantPattern: "github.com/sirupsen/logrus",
+		},
+		{
+			name:       "no match",
+			bans:       []string{"github.com/sirupsen/logrus"},
+			path:       "github.com/pkg/errors",
+			wantBanned: false,
+		},
+		{
+			name:        "wildcard match",
+			bans:        []string{"github.com/sirupsen/..."},
+			path:        "github.com/sirupsen/logrus",
+			wantBanned:  true,
+			wantPattern: "github.com/sirupsen/...",
+		},
+		{
+			name:       "exception overrides broader ban",
+			bans:       []string{"github.com/foo/...", "!github.com/foo/allowed"},
+			path:       "github.com/foo/allowed",
+			wantBanned: false,
+		},
+		{
+			name:        "exception does not affect siblings",
+			bans:        []string{"github.com/foo/...", "!github.com/foo/allowed"},
+			path:        "github.com/foo/banned",
+			wantBanned:  true,
+			wantPattern: "github.com/foo/...",
+		},
+		{
+			name:       "exception order does not matter",
+			bans:       []string{"!github.com/foo/allowed", "github.com/foo/..."},
+			path:       "github.com/foo/allowed",
+			wantBanned: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := compileBans(tt.bans)
+			pattern, banned := matchBanned(rules, tt.path)
+			if banned != tt.wantBanned {
+				t.Fatalf("matchBanned(%v, %q) banned = %v, want %v", tt.bans, tt.path, banned, tt.wantBanned)
+			}
+			if banned && pattern != tt.wantPattern {
+				t.Fatalf("matchBanned(%v, %q) pattern = %q, want %q", tt.bans, tt.path, pattern, tt.wantPattern)
+			}
+		})
+	}
+}
+
+func TestFilterSkipped(t *testing.T) {
+	tests := []struct {
+		name  string
+		pkgs  []pkgRef
+		skips []string
+		want  []pkgRef
+	}{
+		{
+			name:  "no skips",
+			pkgs:  []pkgRef{{Name: "./foo", Dir: "./foo"}},
+			skips: nil,
+			want:  []pkgRef{{Name: "./foo", Dir: "./foo"}},
+		},
+		{
+			name: "skip by Name in filesystem mode",
+			pkgs: []pkgRef{
+				{Name: "./foo", Dir: "./foo"},
+				{Name: "./internal/legacy", Dir: "./internal/legacy"},
+			},
+			skips: []string{"./internal/legacy"},
+			want:  []pkgRef{{Name: "./foo", Dir: "./foo"}},
+		},
+		{
+			name: "skip by Name in import-path mode even though Dir is an absolute path",
+			pkgs: []pkgRef{
+				{Name: "example.com/foo/good", Dir: "/go/src/example.com/foo/good"},
+				{Name: "example.com/foo/legacy", Dir: "/go/src/example.com/foo/legacy"},
+			},
+			skips: []string{"example.com/foo/legacy/..."},
+			want:  []pkgRef{{Name: "example.com/foo/good", Dir: "/go/src/example.com/foo/good"}},
+		},
+		{
+			name: "wildcard skip",
+			pkgs: []pkgRef{
+				{Name: "example.com/foo/good", Dir: "/d1"},
+				{Name: "example.com/foo/legacy/a", Dir: "/d2"},
+				{Name: "example.com/foo/legacy/b", Dir: "/d3"},
+			},
+			skips: []string{"example.com/foo/legacy/..."},
+			want:  []pkgRef{{Name: "example.com/foo/good", Dir: "/d1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterSkipped(tt.pkgs, tt.skips)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("filterSkipped() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		violations []violation
+	}{
+		{
+			name:       "no violations",
+			violations: nil,
+		},
+		{
+			name: "one violation",
+			violations: []violation{
+				{Package: "mypkg", File: "mypkg/main.go", Line: 3, Import: "github.com/sirupsen/logrus", Ban: "github.com/sirupsen/..."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := reportJSON(&buf, tt.violations); err != nil {
+				t.Fatalf("reportJSON() error = %v", err)
+			}
+
+			var got []violation
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("reportJSON() produced invalid JSON: %v\n%s", err, buf.String())
+			}
+			if len(got) != len(tt.violations) {
+				t.Fatalf("reportJSON() = %#v, want %#v", got, tt.violations)
+			}
+			for i := range tt.violations {
+				if !reflect.DeepEqual(got[i], tt.violations[i]) {
+					t.Fatalf("reportJSON()[%d] = %#v, want %#v", i, got[i], tt.violations[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReportCheckstyle(t *testing.T) {
+	tests := []struct {
+		name       string
+		violations []violation
+		wantFiles  int
+		wantErrs   int
+	}{
+		{
+			name:       "no violations",
+			violations: nil,
+			wantFiles:  0,
+		},
+		{
+			name: "violations grouped by file",
+			violations: []violation{
+				{Package: "mypkg", File: "mypkg/main.go", Line: 3, Import: "github.com/sirupsen/logrus", Ban: "github.com/sirupsen/..."},
+				{Package: "mypkg", File: "mypkg/main.go", Line: 9, Import: "github.com/sirupsen/logrus/hooks", Ban: "github.com/sirupsen/..."},
+				{Package: "mypkg/sub", File: "mypkg/sub/sub.go", Line: 1, Import: "github.com/sirupsen/logrus", Ban: "github.com/sirupsen/..."},
+			},
+			wantFiles: 2,
+			wantErrs:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := reportCheckstyle(&buf, tt.violations); err != nil {
+				t.Fatalf("reportCheckstyle() error = %v", err)
+			}
+
+			var got checkstyleReport
+			if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("reportCheckstyle() produced invalid XML: %v\n%s", err, buf.String())
+			}
+			if got.Version != "4.3" {
+				t.Fatalf("reportCheckstyle() version = %q, want %q", got.Version, "4.3")
+			}
+			if len(got.Files) != tt.wantFiles {
+				t.Fatalf("reportCheckstyle() files = %d, want %d", len(got.Files), tt.wantFiles)
+			}
+			var errs int
+			for _, f := range got.Files {
+				errs += len(f.Errors)
+			}
+			if errs != tt.wantErrs {
+				t.Fatalf("reportCheckstyle() errors = %d, want %d", errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+// writeFakePkg creates a single-file package at importPath under gopath,
+// so build.Import can resolve it the same way it would a real GOPATH
+// dependency.
+func writeFakePkg(t *testing.T, gopath, importPath string, imports ...string) {
+	t.Helper()
+	dir := filepath.Join(gopath, "src", filepath.FromSlash(importPath))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	_, name := filepath.Split(importPath)
+	src := "package " + name + "\n"
+	for _, imp := range imports {
+		src += "import _ \"" + imp + "\"\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// withGOPATH points build.Default at a scratch GOPATH for the duration of
+// the test, so writeFakePkg's fixtures are what build.Import resolves.
+func withGOPATH(t *testing.T) string {
+	t.Helper()
+	gopath := t.TempDir()
+	orig := build.Default.GOPATH
+	build.Default.GOPATH = gopath
+	t.Cleanup(func() { build.Default.GOPATH = orig })
+	return gopath
+}
+
+func TestCheckTransitiveImports(t *testing.T) {
+	t.Run("depth boundary", func(t *testing.T) {
+		gopath := withGOPATH(t)
+		// direct import mypkg/foo -> mypkg/bar (1 hop) -> banned/pkg (2 hops).
+		writeFakePkg(t, gopath, "mypkg/foo", "mypkg/bar")
+		writeFakePkg(t, gopath, "mypkg/bar", "banned/pkg")
+		writeFakePkg(t, gopath, "banned/pkg")
+
+		rules := compileBans([]string{"banned/..."})
+		origins := map[string]importSite{"mypkg/foo": {File: "mypkg/main.go", Line: 3}}
+		pkgDir := filepath.Join(gopath, "src", "mypkg")
+
+		for _, tt := range []struct {
+			maxDepth int
+			want     bool
+		}{
+			{maxDepth: 1, want: false},
+			{maxDepth: 2, want: true},
+		} {
+			opts := checkOptions{transitive: true, maxDepth: tt.maxDepth}
+			got := checkTransitiveImports("mypkg", pkgDir, []string{"mypkg/foo"}, origins, rules, opts)
+			if found := len(got) > 0; found != tt.want {
+				t.Fatalf("max-depth=%d: found banned import = %v, want %v (violations: %#v)", tt.maxDepth, found, tt.want, got)
+			}
+			if tt.want {
+				want := "mypkg -> mypkg/foo -> mypkg/bar -> banned/pkg"
+				if got[0].Import != want {
+					t.Fatalf("max-depth=%d: Import = %q, want %q", tt.maxDepth, got[0].Import, want)
+				}
+				if got[0].File != "mypkg/main.go" || got[0].Line != 3 {
+					t.Fatalf("max-depth=%d: File/Line = %s:%d, want mypkg/main.go:3", tt.maxDepth, got[0].File, got[0].Line)
+				}
+			}
+		}
+	})
+
+	t.Run("cycle handling", func(t *testing.T) {
+		gopath := withGOPATH(t)
+		writeFakePkg(t, gopath, "mypkg/foo", "mypkg/bar")
+		writeFakePkg(t, gopath, "mypkg/bar", "mypkg/foo") // cycle back to foo
+
+		rules := compileBans([]string{"banned/..."})
+		origins := map[string]importSite{"mypkg/foo": {File: "mypkg/main.go", Line: 1}}
+		pkgDir := filepath.Join(gopath, "src", "mypkg")
+		opts := checkOptions{transitive: true, maxDepth: 10}
+
+		done := make(chan []violation, 1)
+		go func() {
+			done <- checkTransitiveImports("mypkg", pkgDir, []string{"mypkg/foo"}, origins, rules, opts)
+		}()
+		select {
+		case got := <-done:
+			if len(got) != 0 {
+				t.Fatalf("checkTransitiveImports() = %#v, want no violations", got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("checkTransitiveImports() did not return, likely stuck on the import cycle")
+		}
+	})
+
+	t.Run("skips stdlib unless include-std", func(t *testing.T) {
+		gopath := withGOPATH(t)
+		writeFakePkg(t, gopath, "mypkg/foo", "mypkg/bar")
+		writeFakePkg(t, gopath, "mypkg/bar", "fmt")
+
+		rules := compileBans([]string{"fmt"})
+		origins := map[string]importSite{"mypkg/foo": {File: "mypkg/main.go", Line: 1}}
+		pkgDir := filepath.Join(gopath, "src", "mypkg")
+
+		opts := checkOptions{transitive: true, maxDepth: 10}
+		if got := checkTransitiveImports("mypkg", pkgDir, []string{"mypkg/foo"}, origins, rules, opts); len(got) != 0 {
+			t.Fatalf("checkTransitiveImports() without -include-std = %#v, want no violations", got)
+		}
+
+		opts.includeStd = true
+		if got := checkTransitiveImports("mypkg", pkgDir, []string{"mypkg/foo"}, origins, rules, opts); len(got) != 1 {
+			t.Fatalf("checkTransitiveImports() with -include-std = %#v, want one violation", got)
+		}
+	})
+
+	t.Run("diamond reaches the shallow route after the deep one", func(t *testing.T) {
+		gopath := withGOPATH(t)
+		// mypkg/deep reaches mypkg/common 3 hops in, exhausting maxDepth
+		// before common's own import of banned/pkg; mypkg/shallow reaches
+		// the same mypkg/common 1 hop in, with budget to spare. If
+		// visiting mypkg/common via the deep route first "claimed" it,
+		// the shallow route's extra budget would never get used and the
+		// banned import would go unreported.
+		writeFakePkg(t, gopath, "mypkg/deep", "mypkg/mid1")
+		writeFakePkg(t, gopath, "mypkg/mid1", "mypkg/mid2")
+		writeFakePkg(t, gopath, "mypkg/mid2", "mypkg/common")
+		writeFakePkg(t, gopath, "mypkg/shallow", "mypkg/common")
+		writeFakePkg(t, gopath, "mypkg/common", "banned/pkg")
+		writeFakePkg(t, gopath, "banned/pkg")
+
+		rules := compileBans([]string{"banned/..."})
+		origins := map[string]importSite{
+			"mypkg/deep":    {File: "mypkg/main.go", Line: 1},
+			"mypkg/shallow": {File: "mypkg/main.go", Line: 2},
+		}
+		pkgDir := filepath.Join(gopath, "src", "mypkg")
+		opts := checkOptions{transitive: true, maxDepth: 3}
+
+		got := checkTransitiveImports("mypkg", pkgDir, []string{"mypkg/deep", "mypkg/shallow"}, origins, rules, opts)
+		if len(got) != 1 {
+			t.Fatalf("checkTransitiveImports() = %#v, want exactly one violation via the shallow route", got)
+		}
+		want := "mypkg -> mypkg/shallow -> mypkg/common -> banned/pkg"
+		if got[0].Import != want {
+			t.Fatalf("Import = %q, want %q", got[0].Import, want)
+		}
+		if got[0].File != "mypkg/main.go" || got[0].Line != 2 {
+			t.Fatalf("File/Line = %s:%d, want mypkg/main.go:2", got[0].File, got[0].Line)
+		}
+	})
+
+	t.Run("two direct imports converging on the same dependency both get reported", func(t *testing.T) {
+		gopath := withGOPATH(t)
+		// mypkg/depA and mypkg/depB both import mypkg/common, which
+		// imports banned/pkg. Each is its own import site and needs its
+		// own reported violation; one must not suppress the other just
+		// because it happened to reach common first.
+		writeFakePkg(t, gopath, "mypkg/depA", "mypkg/common")
+		writeFakePkg(t, gopath, "mypkg/depB", "mypkg/common")
+		writeFakePkg(t, gopath, "mypkg/common", "banned/pkg")
+		writeFakePkg(t, gopath, "banned/pkg")
+
+		rules := compileBans([]string{"banned/..."})
+		origins := map[string]importSite{
+			"mypkg/depA": {File: "mypkg/main.go", Line: 1},
+			"mypkg/depB": {File: "mypkg/main.go", Line: 2},
+		}
+		pkgDir := filepath.Join(gopath, "src", "mypkg")
+		opts := checkOptions{transitive: true, maxDepth: 5}
+
+		got := checkTransitiveImports("mypkg", pkgDir, []string{"mypkg/depA", "mypkg/depB"}, origins, rules, opts)
+		if len(got) != 2 {
+			t.Fatalf("checkTransitiveImports() = %#v, want one violation per direct import", got)
+		}
+
+		byLine := map[int]violation{}
+		for _, v := range got {
+			byLine[v.Line] = v
+		}
+		wantA := "mypkg -> mypkg/depA -> mypkg/common -> banned/pkg"
+		if v, ok := byLine[1]; !ok || v.Import != wantA || v.File != "mypkg/main.go" {
+			t.Fatalf("violation for line 1 = %#v, want Import %q at mypkg/main.go:1", v, wantA)
+		}
+		wantB := "mypkg -> mypkg/depB -> mypkg/common -> banned/pkg"
+		if v, ok := byLine[2]; !ok || v.Import != wantB || v.File != "mypkg/main.go" {
+			t.Fatalf("violation for line 2 = %#v, want Import %q at mypkg/main.go:2", v, wantB)
+		}
+	})
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "github.com/foo/bar", name: "github.com/foo/bar", want: true},
+		{pattern: "github.com/foo/bar", name: "github.com/foo/baz", want: false},
+		{pattern: "github.com/foo/...", name: "github.com/foo/bar", want: true},
+		{pattern: "github.com/foo/...", name: "github.com/foo/bar/baz", want: true},
+		{pattern: "github.com/foo/...", name: "github.com/bar/baz", want: false},
+		// Special case: foo/... also matches foo itself, same as cmd/go.
+		{pattern: "github.com/foo/...", name: "github.com/foo", want: true},
+		{pattern: "./...", name: "./foo/bar", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" vs "+tt.name, func(t *testing.T) {
+			if got := matchPattern(tt.pattern)(tt.name); got != tt.want {
+				t.Fatalf("matchPattern(%q)(%q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// writeGoFile creates a trivial single-file package at path, enough for
+// build.ImportDir to recognize the directory as a Go package.
+func writeGoFile(t *testing.T, path, pkgName string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("package "+pkgName+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// withCWD chdirs into dir for the duration of the test, restoring the
+// original working directory on cleanup, the same way withGOPATH restores
+// build.Default.GOPATH.
+func withCWD(t *testing.T, dir string) {
+	t.Helper()
+	orig, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(orig) })
+}
+
+func pkgNames(pkgs []pkgRef) []string {
+	names := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		names[i] = p.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestMatchPackagesInFS(t *testing.T) {
+	root := t.TempDir()
+	writeGoFile(t, filepath.Join(root, "foo", "foo.go"), "foo")
+	writeGoFile(t, filepath.Join(root, "foo", "bar", "bar.go"), "bar")
+	writeGoFile(t, filepath.Join(root, "vendor", "v", "v.go"), "v")
+	writeGoFile(t, filepath.Join(root, "testdata", "t.go"), "testdata")
+	withCWD(t, root)
+
+	got, err := matchPackagesInFS("./...")
+	if err != nil {
+		t.Fatalf("matchPackagesInFS() error = %v", err)
+	}
+
+	want := []string{"./foo", "./foo/bar"}
+	if names := pkgNames(got); !reflect.DeepEqual(names, want) {
+		t.Fatalf("matchPackagesInFS(\"./...\") = %v, want %v", names, want)
+	}
+	for _, p := range got {
+		if p.Name != p.Dir {
+			t.Fatalf("filesystem mode Name != Dir: %#v", p)
+		}
+	}
+}
+
+func TestAllPackagesInFS(t *testing.T) {
+	root := t.TempDir()
+	writeGoFile(t, filepath.Join(root, "foo", "foo.go"), "foo")
+	withCWD(t, root)
+
+	if names := pkgNames(allPackagesInFS("./...")); !reflect.DeepEqual(names, []string{"./foo"}) {
+		t.Fatalf("allPackagesInFS(\"./...\") = %v, want [./foo]", names)
+	}
+}
+
+func TestMatchPackages(t *testing.T) {
+	t.Run("std meta-name resolves real GOROOT packages, excluding cmd", func(t *testing.T) {
+		names := pkgNames(MatchPackages("std"))
+		found := false
+		for _, n := range names {
+			if n == "fmt" {
+				found = true
+			}
+			if n == "cmd" || strings.HasPrefix(n, "cmd/") {
+				t.Fatalf("MatchPackages(\"std\") included %q, want std and cmd disjoint", n)
+			}
+		}
+		if !found {
+			t.Fatalf("MatchPackages(\"std\") = %v, want it to include %q", names, "fmt")
+		}
+	})
+
+	t.Run("all meta-name walks GOPATH, not GOROOT", func(t *testing.T) {
+		gopath := withGOPATH(t)
+		writeGoFile(t, filepath.Join(gopath, "src", "example.com", "foo", "foo.go"), "foo")
+
+		want := []string{"example.com/foo"}
+		if names := pkgNames(MatchPackages("all")); !reflect.DeepEqual(names, want) {
+			t.Fatalf("MatchPackages(\"all\") = %v, want %v", names, want)
+		}
+	})
+
+	t.Run("cmd/...-rooted pattern resolves the same set as the cmd meta-name", func(t *testing.T) {
+		meta := pkgNames(MatchPackages("cmd"))
+		rooted := pkgNames(MatchPackages("cmd/..."))
+		if len(meta) == 0 {
+			t.Fatal("MatchPackages(\"cmd\") = [], want at least one package")
+		}
+		if !reflect.DeepEqual(meta, rooted) {
+			t.Fatalf("MatchPackages(\"cmd/...\") = %v, want the same set as MatchPackages(\"cmd\") = %v", rooted, meta)
+		}
+		for _, n := range rooted {
+			if n != "cmd" && !strings.HasPrefix(n, "cmd/") {
+				t.Fatalf("MatchPackages(\"cmd/...\") matched %q, want it rooted at cmd", n)
+			}
+		}
+	})
+}
+
+func TestResolvePackages(t *testing.T) {
+	t.Run("filesystem pattern dispatches to allPackagesInFS", func(t *testing.T) {
+		root := t.TempDir()
+		writeGoFile(t, filepath.Join(root, "foo", "foo.go"), "foo")
+		withCWD(t, root)
+
+		if names := pkgNames(resolvePackages("./...")); !reflect.DeepEqual(names, []string{"./foo"}) {
+			t.Fatalf("resolvePackages(\"./...\") = %v, want [./foo]", names)
+		}
+	})
+
+	t.Run("single relative directory passes through as-is", func(t *testing.T) {
+		want := []pkgRef{{Name: "./foo", Dir: "./foo"}}
+		if got := resolvePackages("./foo"); !reflect.DeepEqual(got, want) {
+			t.Fatalf("resolvePackages(%q) = %#v, want %#v", "./foo", got, want)
+		}
+	})
+
+	t.Run("single absolute import path resolves its real directory", func(t *testing.T) {
+		gopath := withGOPATH(t)
+		dir := filepath.Join(gopath, "src", "example.com", "foo", "bar")
+		writeGoFile(t, filepath.Join(dir, "bar.go"), "bar")
+
+		want := []pkgRef{{Name: "example.com/foo/bar", Dir: dir}}
+		if got := resolvePackages("example.com/foo/bar"); !reflect.DeepEqual(got, want) {
+			t.Fatalf("resolvePackages(%q) = %#v, want %#v", "example.com/foo/bar", got, want)
+		}
+	})
+
+	t.Run("meta-name dispatches to MatchPackages", func(t *testing.T) {
+		gopath := withGOPATH(t)
+		writeGoFile(t, filepath.Join(gopath, "src", "example.com", "foo", "foo.go"), "foo")
+
+		want := []string{"example.com/foo"}
+		if names := pkgNames(resolvePackages("all")); !reflect.DeepEqual(names, want) {
+			t.Fatalf("resolvePackages(\"all\") = %v, want %v", names, want)
+		}
+	})
+}